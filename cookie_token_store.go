@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// cookieTokenStorePrefix is prepended to the oauth_token to form the cookie
+// name, so concurrent logins in the same browser don't collide.
+const cookieTokenStorePrefix = "oauth1_token_"
+
+// CookieTokenStore stores the oauth_token_secret HMAC-signed in a cookie on
+// the user's browser, instead of server-side memory, so the temporary secret
+// survives across instances without any shared storage.
+//
+// It is scoped to a single request: construct one with NewCookieTokenStore
+// and assign it to OAuth1Mixin.Tokens before calling AuthorizeRedirect, then
+// construct another for the callback request and assign it before calling
+// AuthorizeToken, so it can read the cookie back.
+type CookieTokenStore struct {
+	w         http.ResponseWriter
+	r         *http.Request
+	secretKey []byte
+}
+
+// NewCookieTokenStore returns a CookieTokenStore scoped to a single request,
+// signing and verifying cookie values with secretKey.
+func NewCookieTokenStore(w http.ResponseWriter, r *http.Request, secretKey []byte) *CookieTokenStore {
+	return &CookieTokenStore{w: w, r: r, secretKey: secretKey}
+}
+
+func (s *CookieTokenStore) cookieName(token string) string {
+	return cookieTokenStorePrefix + token
+}
+
+func (s *CookieTokenStore) sign(token, secret string) string {
+	mac := hmac.New(sha1.New, s.secretKey)
+	mac.Write([]byte(token + "." + secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *CookieTokenStore) Get(token string) (string, bool) {
+	cookie, err := s.r.Cookie(s.cookieName(token))
+	if err != nil {
+		return "", false
+	}
+
+	raw, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return "", false
+	}
+
+	idx := strings.LastIndex(raw, ".")
+	if idx < 0 {
+		return "", false
+	}
+	secret, signature := raw[:idx], raw[idx+1:]
+	if !hmac.Equal([]byte(signature), []byte(s.sign(token, secret))) {
+		return "", false
+	}
+	return secret, true
+}
+
+func (s *CookieTokenStore) Set(token, secret string, ttl time.Duration) {
+	value := secret + "." + s.sign(token, secret)
+	http.SetCookie(s.w, &http.Cookie{
+		Name:     s.cookieName(token),
+		Value:    url.QueryEscape(value),
+		Path:     "/",
+		Expires:  time.Now().Add(ttl),
+		HttpOnly: true,
+	})
+}
+
+func (s *CookieTokenStore) Delete(token string) {
+	http.SetCookie(s.w, &http.Cookie{
+		Name:    s.cookieName(token),
+		Value:   "",
+		Path:    "/",
+		Expires: time.Unix(0, 0),
+		MaxAge:  -1,
+	})
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestCookieTokenStoreRoundTrip checks that a secret set by one request's
+// CookieTokenStore can be read back by another's, as happens between the
+// redirect and callback legs of the OAuth1.0a dance.
+func TestCookieTokenStoreRoundTrip(t *testing.T) {
+	secretKey := []byte("secretkey")
+
+	rec := httptest.NewRecorder()
+	setStore := NewCookieTokenStore(rec, httptest.NewRequest("GET", "/", nil), secretKey)
+	setStore.Set("token", "tokensecret", time.Hour)
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookies[0])
+	getStore := NewCookieTokenStore(httptest.NewRecorder(), req, secretKey)
+
+	secret, ok := getStore.Get("token")
+	if !ok || secret != "tokensecret" {
+		t.Fatalf("Get(token) = (%q, %v), want (\"tokensecret\", true)", secret, ok)
+	}
+
+	if _, ok := getStore.Get("othertoken"); ok {
+		t.Errorf("Get(othertoken) ok = true, want false for a token with no cookie")
+	}
+}
+
+// TestCookieTokenStoreTamperedCookie checks that a cookie whose value was
+// modified after signing is rejected rather than returning the tampered
+// secret.
+func TestCookieTokenStoreTamperedCookie(t *testing.T) {
+	secretKey := []byte("secretkey")
+
+	rec := httptest.NewRecorder()
+	setStore := NewCookieTokenStore(rec, httptest.NewRequest("GET", "/", nil), secretKey)
+	setStore.Set("token", "tokensecret", time.Hour)
+
+	cookie := rec.Result().Cookies()[0]
+	cookie.Value = cookie.Value + "tampered"
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	getStore := NewCookieTokenStore(httptest.NewRecorder(), req, secretKey)
+
+	if _, ok := getStore.Get("token"); ok {
+		t.Errorf("Get(token) ok = true for a tampered cookie, want false")
+	}
+}
@@ -1,69 +1,169 @@
 package auth
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
-	//"strings"
+	"strings"
 	"time"
-
-	"code.google.com/p/vitess/go/cache"
 )
 
-// cache used to store the oauth_token_secret between sessions. By default it
-// stores 1MB of data. When the limit is reached the cache will clear out older
-// items (which by the time they are removed from the cache should not be
-// needed anymore). 
-var tokenCache = cache.NewLRUCache(1048576) 
+// Abstract implementation of OAuth1.0a for user authentication.
+type OAuth1Mixin struct {
+	AuthorizeUrl string
+	RequestToken string
+	AccessToken  string
+	CallbackUrl  string
+
+	ConsumerKey    string
+	ConsumerSecret string
+
+	// Signer selects the oauth_signature_method used to sign requests. If
+	// nil, HMACSigner is used, matching the historical default.
+	Signer Signer
+
+	// Tokens stores the oauth_token_secret between AuthorizeRedirect and
+	// AuthorizeToken. If nil, an in-process LRU cache is used, which does
+	// not survive restarts and only works for single-instance deployments.
+	Tokens TokenStore
+
+	// TokenTTL bounds how long a request token's secret is retained by
+	// Tokens. Defaults to DefaultTokenTTL.
+	TokenTTL time.Duration
+
+	// OOB requests an out-of-band (PIN-based) verifier instead of an HTTP
+	// callback, per RFC 5849 §2.1: the provider displays a PIN to the user
+	// rather than redirecting back to CallbackUrl. Complete this flow with
+	// AuthorizeTokenWithVerifier once the user has entered the PIN, which is
+	// how CLI/desktop applications without a reachable callback URL
+	// authenticate.
+	OOB bool
+}
 
-// tokenCacheItem represents an OAuth token that implements the cache.Value
-// interface, and can therefore be stored in the LRUCache.
-//type tokenCacheItem string;
-//func (i tokenCacheItem) Size()   int    { return len(i) }
-//func (i tokenCacheItem) String() string { return string(i) }
+// ErrTokenSecretNotFound is returned by AuthorizeToken when no secret is
+// found in Tokens for the oauth_token on the callback request, e.g. because
+// it expired or AuthorizeRedirect was never completed for it.
+var ErrTokenSecretNotFound = errors.New("auth: no token secret found for oauth_token")
+
+// tokens returns the TokenStore configured on the mixin, falling back to the
+// default in-process LRU for backwards compatibility with existing
+// consumers.
+func (self *OAuth1Mixin) tokens() TokenStore {
+	if self.Tokens != nil {
+		return self.Tokens
+	}
+	return defaultTokenStore
+}
 
+// tokenTTL returns the configured TokenTTL, or DefaultTokenTTL if unset.
+func (self *OAuth1Mixin) tokenTTL() time.Duration {
+	if self.TokenTTL > 0 {
+		return self.TokenTTL
+	}
+	return DefaultTokenTTL
+}
 
+// signer returns the Signer configured on the mixin, falling back to
+// HMAC-SHA1 for backwards compatibility with consumers that don't set one.
+func (self *OAuth1Mixin) signer() Signer {
+	if self.Signer != nil {
+		return self.Signer
+	}
+	return HMACSigner{}
+}
 
-// requestToken stores the values returned when requesting a request token. The
-// request token is used to obtain authorization from a user, and exchanged
-// for an access token.
-type requestToken {
-	Token  string // the oauth_token value
-	Secret string // the oauth_token_secret value
+// Signer signs an OAuth1.0a request base string with the consumer/token
+// secret key, and reports the oauth_signature_method it implements. It lets
+// callers support providers that don't use the default HMAC-SHA1 method, such
+// as Bitbucket Server/Stash (RSA-SHA1) or providers accessed over a trusted
+// channel (PLAINTEXT).
+type Signer interface {
+	// Name returns the oauth_signature_method value for this Signer.
+	Name() string
+
+	// Sign returns the signature of base using key, encoded as required by
+	// this Signer's method: base64 for HMAC-SHA1 and RSA-SHA1, or key itself,
+	// unencoded, for PLAINTEXT.
+	Sign(base, key string) (string, error)
 }
 
-// Gets the size (in bytes) of the Token. This is used to implement the
-// cache.Value interface, allowing this struct to be stored in the LRUCache.
-func (t requestToken) Size() int {
-	return len(t.Token) + len(t.Secret)
+// HMACSigner signs requests with HMAC-SHA1, the default OAuth1.0a signature
+// method.
+type HMACSigner struct{}
+
+func (HMACSigner) Name() string { return "HMAC-SHA1" }
+
+func (HMACSigner) Sign(base, key string) (string, error) {
+	return sign(base, key), nil
+}
+
+// PlaintextSigner signs requests with the PLAINTEXT method, where the
+// signature is simply the signing key. It should only be used over a
+// transport that protects the key in transit, such as HTTPS.
+type PlaintextSigner struct{}
+
+func (PlaintextSigner) Name() string { return "PLAINTEXT" }
+
+func (PlaintextSigner) Sign(base, key string) (string, error) {
+	return key, nil
 }
 
-// accessToken stores the values returned when upgrading a request token
-// to an access token. The access token gives the consumer access to the
-// User's protected resources.
-type accessToken {
-	Token  string // the oauth_token value
-	Secret string // the oauth_token_secret value
+// RSASigner signs requests with RSA-SHA1, as required by providers that
+// authenticate the consumer with a registered public key instead of a shared
+// secret (e.g. Bitbucket Server/Stash and OpenStack Keystone via Application
+// Links).
+type RSASigner struct {
+	PrivateKey *rsa.PrivateKey
 }
 
-// Abstract implementation of OAuth2 for user authentication.
-type OAuth1Mixin struct {
-	AuthorizeUrl    string
-	RequestToken    string
-	AccessToken     string
-	CallbackUrl     string
+// NewRSASignerFromPEM parses a PEM-encoded PKCS#1 or PKCS#8 RSA private key
+// and returns an RSASigner wrapping it.
+func NewRSASignerFromPEM(pemBytes []byte) (*RSASigner, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("auth: no PEM block found in RSA private key")
+	}
 
-	ConsumerKey     string
-	ConsumerSecret  string
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return &RSASigner{PrivateKey: key}, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("auth: PEM private key is not an RSA key")
+	}
+	return &RSASigner{PrivateKey: rsaKey}, nil
+}
+
+func (RSASigner) Name() string { return "RSA-SHA1" }
+
+func (s RSASigner) Sign(base, key string) (string, error) {
+	hashed := sha1.Sum([]byte(base))
+	signature, err := rsa.SignPKCS1v15(cryptorand.Reader, s.PrivateKey, crypto.SHA1, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
 }
 
 // RedirectRequired returns a boolean value indicating if the request should
@@ -80,61 +180,90 @@ func (self *OAuth1Mixin) RedirectRequired(r *http.Request) bool {
 // the oauth_verifier to the callback URL.
 func (self *OAuth1Mixin) AuthorizeRedirect(w http.ResponseWriter, r *http.Request,
 	endpoint string, params url.Values) error {
+	return self.authorizeRedirect(w, r, endpoint, "GET", nil, params)
+}
 
-	//create the http request to fetch a Request Token.
-	requestTokenUrl, _ := url.Parse(self.RequestToken)
-	req := http.Request{
-		URL:        requestTokenUrl,
-		Method:     "GET",
-		ProtoMajor: 1,
-		ProtoMinor: 1,
-		Close:      true,
+// AuthorizeRedirectForm is like AuthorizeRedirect, but requests the Request
+// Token with method and form instead of a plain GET. This is required by
+// providers such as Bitbucket Server/Stash and OpenStack Keystone, which
+// only accept a form-encoded POST for this step.
+func (self *OAuth1Mixin) AuthorizeRedirectForm(w http.ResponseWriter, r *http.Request,
+	endpoint, method string, form url.Values, params url.Values) error {
+	return self.authorizeRedirect(w, r, endpoint, method, form, params)
+}
+
+func (self *OAuth1Mixin) authorizeRedirect(w http.ResponseWriter, r *http.Request,
+	endpoint, method string, form url.Values, params url.Values) error {
+
+	loginUrl, _, err := self.RequestLoginURL(endpoint, method, form, params)
+	if err != nil {
+		return err
 	}
 
+	// redirect to login
+	http.Redirect(w, r, loginUrl, http.StatusSeeOther)
+	return nil
+}
+
+// RequestLoginURL performs the request-token step of the OAuth1.0a dance and
+// returns the URL the user should be sent to at endpoint to authorize us,
+// together with the oauth_token it's keyed on, without requiring a live
+// http.ResponseWriter or *http.Request. AuthorizeRedirect/AuthorizeRedirectForm
+// use this internally to build the URL before redirecting.
+//
+// A CLI/desktop application doing the out-of-band (OOB) flow should call
+// this directly: print the returned URL for the user to open in a browser,
+// then prompt them for the PIN the provider displays and finish with
+// AuthorizeTokenWithVerifier.
+func (self *OAuth1Mixin) RequestLoginURL(endpoint, method string, form url.Values, params url.Values) (string, string, error) {
+
 	//set the header variables (using defualts), and add the callback URL
+	//(or the "oob" sentinel, for out-of-band/PIN-based verification)
 	headers := self.headers()
-	headers["oauth_callback"] = self.CallbackUrl
-	
-	//sign the request ...
-	key := url.QueryEscape(self.ConsumerSecret) + "&" + url.QueryEscape("")
-	base := requestString(req.Method, req.URL.String(), headers)
-	headers["oauth_signature"] = sign(base, key)
+	if self.OOB {
+		headers["oauth_callback"] = "oob"
+	} else {
+		headers["oauth_callback"] = self.CallbackUrl
+	}
 
-	//add the Authorization header to the request
-	req.Header = http.Header{}
-	req.Header.Add("Authorization", authorizationString(headers))
+	//sign and build the request to fetch a Request Token.
+	key := rfc3986Escape(self.ConsumerSecret) + "&" + rfc3986Escape("")
+	req, err := self.signedRequest(method, self.RequestToken, headers, key, form, nil)
+	if err != nil {
+		return "", "", err
+	}
 
 	//make the http request and get the response
-	resp, err := http.DefaultClient.Do(&req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
 	//get the request body
 	body, err := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
 	//parse the request token from the body
 	parts, err := url.ParseQuery(string(body))
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	//now we have the request token, we can re-direct the user to the
-	//login screen to authorize us.
+	//now we have the request token, we can build the URL to send the user to
+	//the login screen to authorize us.
 	requestToken := parts.Get("oauth_token")
 	secretToken := parts.Get("oauth_token_secret")
-	if len(requestToken)==0 || len(secretToken)==0 {
-		return errors.New(string(body))
+	if len(requestToken) == 0 || len(secretToken) == 0 {
+		return "", "", errors.New(string(body))
 	}
 
 	//add the oauth_token_secret to the cache
-	tokenCache.Set(requestToken, tokenCacheItem(secretToken))
+	self.tokens().Set(requestToken, secretToken, self.tokenTTL())
 
-	//create the URL params, if a nil value was passed to this function	
+	//create the URL params, if a nil value was passed to this function
 	if params == nil {
 		params = make(url.Values)
 	}
@@ -146,64 +275,65 @@ func (self *OAuth1Mixin) AuthorizeRedirect(w http.ResponseWriter, r *http.Reques
 	loginUrl, _ := url.Parse(endpoint)
 	loginUrl.RawQuery = params.Encode()
 
-	// redirect to login
-	http.Redirect(w, r, loginUrl.String(), http.StatusSeeOther)
-	return nil
+	return loginUrl.String(), requestToken, nil
 }
 
 // AuthorizeToken trades the Verification Code (oauth_verification) for an
 // Access Token.
 func (self *OAuth1Mixin) AuthorizeToken(r *http.Request) (string, string, error) {
+	queryParams := r.URL.Query()
+	return self.authorizeToken("GET", nil, queryParams.Get("oauth_token"), queryParams.Get("oauth_verifier"))
+}
 
-	//create the http request to fetch a Request Token.
-	accessTokenUrl, _ := url.Parse(self.AccessToken)
-	req := http.Request{
-		URL:        accessTokenUrl,
-		Method:     "GET",
-		ProtoMajor: 1,
-		ProtoMinor: 1,
-		Close:      true,
-	}
-
-	//parse oauth data from Redirect URL
+// AuthorizeTokenForm is like AuthorizeToken, but exchanges the verifier with
+// method and form instead of a plain GET. This is required by providers such
+// as Bitbucket Server/Stash and OpenStack Keystone, which only accept a
+// form-encoded POST for this step.
+func (self *OAuth1Mixin) AuthorizeTokenForm(r *http.Request, method string, form url.Values) (string, string, error) {
 	queryParams := r.URL.Query()
-	token := queryParams.Get("oauth_token")
-	verifier := queryParams.Get("oauth_verifier")
+	return self.authorizeToken(method, form, queryParams.Get("oauth_token"), queryParams.Get("oauth_verifier"))
+}
 
-	//get the secret token from the session cache
-	cachedSecretToken, ok := tokenCache.Get(token)
+// AuthorizeTokenWithVerifier trades token and verifier for an Access Token
+// directly, without reading them off an HTTP callback request. Use this to
+// complete the out-of-band (OOB) flow, where the user copies the verifier
+// PIN the provider displayed into a CLI or desktop application.
+func (self *OAuth1Mixin) AuthorizeTokenWithVerifier(token, verifier string) (string, string, error) {
+	return self.authorizeToken("GET", nil, token, verifier)
+}
+
+func (self *OAuth1Mixin) authorizeToken(method string, form url.Values, token, verifier string) (string, string, error) {
+
+	//get the secret token from the session store
+	secret, ok := self.tokens().Get(token)
 	if !ok {
-		//TODO throw some kind of exception
+		return "", "", ErrTokenSecretNotFound
 	}
+	self.tokens().Delete(token)
 
 	//set the header variables (using defualts), and add the callback URL
 	headers := self.headers()
 	headers["oauth_token"] = token
 	headers["oauth_verifier"] = verifier
 
-	//sign the request ...
-	key := url.QueryEscape(self.ConsumerSecret) + "&" + url.QueryEscape(cachedSecretToken.(tokenCacheItem).String())
-	base := requestString(req.Method, req.URL.String(), headers)
-	headers["oauth_signature"] = sign(base, key)
-
-	//add the Authorization header to the request
-	req.Header = http.Header{}
-	req.Header.Add("Authorization", authorizationString(headers))
-	//req.Header.Add("Content-Type","application/x-www-form-urlencoded")
-	//req.Header.Add("Content-Length",strconv.Itoa(len(verifierString)))
-	//req.Body = ioutil.NopCloser(strings.NewReader(verifierString))
+	//sign and build the request to fetch an Access Token.
+	key := rfc3986Escape(self.ConsumerSecret) + "&" + rfc3986Escape(secret)
+	req, err := self.signedRequest(method, self.AccessToken, headers, key, form, nil)
+	if err != nil {
+		return "", "", err
+	}
 
 	//make the http request and get the response
-	resp, err := http.DefaultClient.Do(&req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "","", err
+		return "", "", err
 	}
 
 	//get the request body
 	body, err := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
-		return "","", err
+		return "", "", err
 	}
 
 	//parse the request token from the body
@@ -236,9 +366,13 @@ func (self *OAuth1Mixin) GetAuthenticatedUser(endpoint, token, secret string, re
 	headers["oauth_token"] = token
 
 	//sign the request ...
-	key := url.QueryEscape(self.ConsumerSecret) + "&" + url.QueryEscape(secret)
+	key := rfc3986Escape(self.ConsumerSecret) + "&" + rfc3986Escape(secret)
 	base := requestString(req.Method, req.URL.String(), headers)
-	headers["oauth_signature"] = sign(base, key)
+	signature, err := self.signer().Sign(base, key)
+	if err != nil {
+		return err
+	}
+	headers["oauth_signature"] = signature
 
 	//add the Authorization header to the request
 	req.Header = http.Header{}
@@ -261,21 +395,104 @@ func (self *OAuth1Mixin) GetAuthenticatedUser(endpoint, token, secret string, re
 	return json.Unmarshal(userData, &resp)
 }
 
+// SignedRequest builds an OAuth1.0a-signed *http.Request for method and
+// rawurl, using token and secret as returned by AuthorizeToken. form is
+// folded into the signature base string and sent as an
+// application/x-www-form-urlencoded body, per RFC 5849 §3.4.1.3.1; if form
+// is nil and body is non-empty, its SHA1 hash is instead included via the
+// oauth_body_hash extension, as required by providers like Bitbucket,
+// Stash, and OpenStack Keystone for non-form request bodies. Use this after
+// GetAuthenticatedUser to make arbitrary authenticated API calls.
+func (self *OAuth1Mixin) SignedRequest(method, rawurl, token, secret string, form url.Values, body io.Reader) (*http.Request, error) {
+	headers := self.headers()
+	headers["oauth_token"] = token
 
+	key := rfc3986Escape(self.ConsumerSecret) + "&" + rfc3986Escape(secret)
+	return self.signedRequest(method, rawurl, headers, key, form, body)
+}
 
+// signedRequest is the shared core behind AuthorizeRedirect, AuthorizeToken
+// and SignedRequest: it folds form (if any) into the signature base string
+// as RFC 5849 §3.4.1.3.1 request body parameters, otherwise hashes body (if
+// any) into an oauth_body_hash parameter, signs the result with key, and
+// returns the resulting *http.Request with its Authorization header and
+// body (if any) set.
+func (self *OAuth1Mixin) signedRequest(method, rawurl string, oauthParams map[string]string, key string, form url.Values, body io.Reader) (*http.Request, error) {
+	reqUrl, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyBytes []byte
+	contentType := ""
+	if len(form) > 0 {
+		bodyBytes = []byte(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else if body != nil {
+		if bodyBytes, err = ioutil.ReadAll(body); err != nil {
+			return nil, err
+		}
+		if len(bodyBytes) > 0 {
+			hashed := sha1.Sum(bodyBytes)
+			bodyHash := base64.StdEncoding.EncodeToString(hashed[:])
+			// oauth_body_hash must also be sent to the provider so it can
+			// recompute the signature base string, not just be folded into
+			// ours.
+			oauthParams["oauth_body_hash"] = bodyHash
+		}
+	}
+
+	base := requestStringMulti(method, reqUrl.String(), oauthParams, form)
+	signature, err := self.signer().Sign(base, key)
+	if err != nil {
+		return nil, err
+	}
+	oauthParams["oauth_signature"] = signature
+
+	req := &http.Request{
+		URL:        reqUrl,
+		Method:     method,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Close:      true,
+		Header:     http.Header{},
+	}
+	req.Header.Add("Authorization", authorizationString(oauthParams))
+
+	if len(bodyBytes) > 0 {
+		req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+		req.ContentLength = int64(len(bodyBytes))
+		if contentType != "" {
+			req.Header.Add("Content-Type", contentType)
+		}
+	}
+
+	return req, nil
+}
 
 // Helper Functions ------------------------------------------------------------
 
 func (self *OAuth1Mixin) headers() map[string]string {
 	return map[string]string{
-		"oauth_consumer_key"     : self.ConsumerKey,
-		"oauth_nonce"            : strconv.FormatInt(rand.New(rand.NewSource(time.Now().Unix())).Int63(), 10),
-		"oauth_signature_method" : "HMAC-SHA1",
-		"oauth_timestamp"        : strconv.FormatInt(time.Now().Unix(), 10),
-		"oauth_version"          : "1.0",
+		"oauth_consumer_key":     self.ConsumerKey,
+		"oauth_nonce":            nonce(),
+		"oauth_signature_method": self.signer().Name(),
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_version":          "1.0",
 	}
 }
 
+// nonce generates an oauth_nonce from crypto/rand. math/rand seeded from
+// time.Now().Unix() only has second resolution, so concurrent requests
+// within the same second previously produced identical, predictable nonces.
+func nonce() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	return hex.EncodeToString(b)
+}
+
 // Generates an HMAC Signature for an OAuth1.0a request.
 func /*(self *OAuth1Mixin)*/ sign(message, key string) string {
 	hashfun := hmac.New(sha1.New, []byte(key))
@@ -287,60 +504,106 @@ func /*(self *OAuth1Mixin)*/ sign(message, key string) string {
 }
 
 
-
-
-
+// requestString builds the OAuth1.0a signature base string per RFC 5849
+// §3.4.1: the uppercased method, the base URI, and the normalized
+// request parameters, each percent-encoded once per RFC 3986 and joined with
+// "&".
 func /*(self *OAuth1Mixin)*/ requestString(method string, uri string, params map[string]string) string {
-	
-	// loop through params, add keys to map
+
 	var keys []string
 	for key, _ := range params {
-		keys = append(keys, key)
+		keys = append(keys, rfc3986Escape(key))
 	}
+	sort.Strings(keys)
 
-	// sort the array of header keys
-	sort.StringSlice(keys).Sort()
+	escaped := make(map[string]string, len(params))
+	for key, value := range params {
+		escaped[rfc3986Escape(key)] = rfc3986Escape(value)
+	}
 
-	// create the signed string
-	result := method + "&" + url.QueryEscape(uri)
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+escaped[key])
+	}
+	paramString := strings.Join(pairs, "&")
 
-	// loop through sorted params and append to the string
-	for pos, key := range keys {
-		if pos == 0 {
-			result += "&"
-		} else {
-			result += url.QueryEscape("&")
+	return strings.ToUpper(method) + "&" + rfc3986Escape(uri) + "&" + rfc3986Escape(paramString)
+}
+
+// requestStringMulti is requestString extended to also fold in form, an
+// RFC 5849 §3.4.1.3.1 request body, without losing repeated parameter names:
+// form can hold multiple values per key, which a map[string]string can't
+// represent. Per §3.4.1.3.2, parameters are normalized by percent-encoding
+// each name/value pair and sorting the results, with repeated names ordered
+// by their (also percent-encoded) value.
+func requestStringMulti(method string, uri string, params map[string]string, form url.Values) string {
+
+	var kvs [][2]string
+	for key, value := range params {
+		kvs = append(kvs, [2]string{rfc3986Escape(key), rfc3986Escape(value)})
+	}
+	for key, values := range form {
+		for _, value := range values {
+			kvs = append(kvs, [2]string{rfc3986Escape(key), rfc3986Escape(value)})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		if kvs[i][0] != kvs[j][0] {
+			return kvs[i][0] < kvs[j][0]
 		}
-		result += url.QueryEscape(fmt.Sprintf("%s=%s", key, url.QueryEscape(params[key])))
+		return kvs[i][1] < kvs[j][1]
+	})
+
+	var pairs []string
+	for _, kv := range kvs {
+		pairs = append(pairs, kv[0]+"="+kv[1])
 	}
+	paramString := strings.Join(pairs, "&")
 
-	return result
+	return strings.ToUpper(method) + "&" + rfc3986Escape(uri) + "&" + rfc3986Escape(paramString)
 }
 
+// authorizationString builds the value of the "Authorization" header per
+// RFC 5849 §3.5.1: "OAuth " followed by the comma-separated, quoted,
+// percent-encoded protocol parameters.
 func /*(self *OAuth1Mixin)*/ authorizationString(params map[string]string) string {
-	
-	// loop through params, add keys to map
+
 	var keys []string
 	for key, _ := range params {
 		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	// sort the array of header keys
-	sort.StringSlice(keys).Sort()
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, rfc3986Escape(key), rfc3986Escape(params[key])))
+	}
 
-	// create the signed string
-	result := "OAuth "
+	return "OAuth " + strings.Join(pairs, ", ")
+}
 
-	// loop through sorted params and append to the string
-	for pos, key := range keys {
-		if pos > 0 {
-			result += ","
+// rfc3986Escape percent-encodes s per RFC 3986 §2.1 / RFC 5849
+// §3.6, leaving only unreserved characters (A-Z a-z 0-9 - . _ ~)
+// unescaped. url.QueryEscape is close but encodes spaces as "+" and isn't
+// specified to match RFC 3986, so signing needs its own encoder.
+func rfc3986Escape(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreservedByte(c) {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
 		}
-		//result += key + "=\"" + url.QueryEscape(params[key]) + "\""
-		result += key + "=\"" + params[key] + "\""
 	}
+	return buf.String()
+}
 
-	return result
+func isUnreservedByte(c byte) bool {
+	return 'A' <= c && c <= 'Z' ||
+		'a' <= c && c <= 'z' ||
+		'0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
 }
 
 /*
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fixedSigner is a Signer stub that always returns itself as the signature,
+// so tests can assert on the Authorization header without depending on the
+// real HMAC/RSA math.
+type fixedSigner string
+
+func (s fixedSigner) Name() string { return "TEST" }
+
+func (s fixedSigner) Sign(base, key string) (string, error) {
+	return string(s), nil
+}
+
+// TestRfc3986Escape checks the percent-encoding rules the OAuth1.0a signature
+// base string depends on: only unreserved characters (RFC 3986 §2.3) pass
+// through unescaped, and everything else -- including a literal "%" from an
+// already-escaped value -- is re-encoded.
+func TestRfc3986Escape(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"abcXYZ019-._~", "abcXYZ019-._~"},
+		{"r b", "r%20b"},
+		{"%3D", "%253D"},
+		{"a@b", "a%40b"},
+		{"=", "%3D"},
+	}
+
+	for _, c := range cases {
+		if got := rfc3986Escape(c.in); got != c.want {
+			t.Errorf("rfc3986Escape(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+// TestRequestString checks the signature base string construction against a
+// reduced version of the RFC 5849 §3.4.1.1 example request: its "a2" query
+// parameter and "oauth_consumer_key" header parameter, encoded and joined
+// per §3.4.1. (The full example also repeats the "a3" and "c2" keys, which
+// this map[string]string-based implementation can't represent.)
+func TestRequestString(t *testing.T) {
+	cases := []struct {
+		method, uri string
+		params      map[string]string
+		want        string
+	}{
+		{
+			method: "get",
+			uri:    "http://example.com/request",
+			params: map[string]string{
+				"a2": "r b",
+			},
+			want: "GET&http%3A%2F%2Fexample.com%2Frequest&a2%3Dr%2520b",
+		},
+		{
+			method: "post",
+			uri:    "http://example.com/request",
+			params: map[string]string{
+				"a2":                 "r b",
+				"oauth_consumer_key": "9djdj82h48djs9d2",
+			},
+			want: "POST&http%3A%2F%2Fexample.com%2Frequest&a2%3Dr%2520b%26oauth_consumer_key%3D9djdj82h48djs9d2",
+		},
+	}
+
+	for _, c := range cases {
+		if got := requestString(c.method, c.uri, c.params); got != c.want {
+			t.Errorf("requestString(%q, %q, %v) = %q, want %q", c.method, c.uri, c.params, got, c.want)
+		}
+	}
+}
+
+// TestRequestStringMulti checks that requestStringMulti, unlike
+// requestString, preserves every value of a repeated form parameter rather
+// than keeping only the first, per RFC 5849 §3.4.1.3.
+func TestRequestStringMulti(t *testing.T) {
+	params := map[string]string{"oauth_consumer_key": "key"}
+	form := url.Values{"a": {"1", "2"}, "b": {"3"}}
+	want := "GET&http%3A%2F%2Fexample.com%2Frequest&a%3D1%26a%3D2%26b%3D3%26oauth_consumer_key%3Dkey"
+
+	if got := requestStringMulti("get", "http://example.com/request", params, form); got != want {
+		t.Errorf("requestStringMulti(...) = %q, want %q", got, want)
+	}
+}
+
+// TestAuthorizationString checks that Authorization header values are
+// percent-encoded per RFC 3986 and quoted/joined per RFC 5849 §3.5.1.
+func TestAuthorizationString(t *testing.T) {
+	params := map[string]string{
+		"realm":           "Example",
+		"oauth_signature": "bYT5CMsGcbgUdFHObYMEfcx6bsw=",
+	}
+	want := `OAuth oauth_signature="bYT5CMsGcbgUdFHObYMEfcx6bsw%3D", realm="Example"`
+
+	if got := authorizationString(params); got != want {
+		t.Errorf("authorizationString(%v) = %q, want %q", params, got, want)
+	}
+}
+
+// TestSignedRequestForm checks that signedRequest folds form into the
+// signature base string, per RFC 5849 §3.4.1.3.1, and sends it as an
+// application/x-www-form-urlencoded body rather than an oauth_body_hash.
+func TestSignedRequestForm(t *testing.T) {
+	mixin := &OAuth1Mixin{Signer: fixedSigner("SIGNATURE")}
+	form := url.Values{"a": {"1"}, "b": {"2"}}
+
+	req, err := mixin.signedRequest("POST", "http://example.com/request-token",
+		map[string]string{"oauth_consumer_key": "key"}, "secretkey", form, nil)
+	if err != nil {
+		t.Fatalf("signedRequest() error = %v", err)
+	}
+
+	if req.Method != "POST" {
+		t.Errorf("Method = %q, want POST", req.Method)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type = %q, want application/x-www-form-urlencoded", ct)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if string(body) != form.Encode() {
+		t.Errorf("body = %q, want %q", body, form.Encode())
+	}
+	if req.ContentLength != int64(len(form.Encode())) {
+		t.Errorf("ContentLength = %d, want %d", req.ContentLength, len(form.Encode()))
+	}
+
+	auth := req.Header.Get("Authorization")
+	if !strings.Contains(auth, `oauth_signature="SIGNATURE"`) {
+		t.Errorf("Authorization = %q, want it to contain oauth_signature=%q", auth, "SIGNATURE")
+	}
+	if strings.Contains(auth, "oauth_body_hash") {
+		t.Errorf("Authorization = %q, should not contain oauth_body_hash for a form request", auth)
+	}
+}
+
+// TestSignedRequestBodyHash checks that signedRequest hashes a non-form body
+// into an oauth_body_hash parameter, and sends it in the Authorization
+// header as well as the signature base string, so the provider can verify
+// the signature against the body it actually received.
+func TestSignedRequestBodyHash(t *testing.T) {
+	mixin := &OAuth1Mixin{Signer: fixedSigner("SIGNATURE")}
+	payload := []byte(`{"hello":"world"}`)
+	sum := sha1.Sum(payload)
+	wantHash := rfc3986Escape(base64.StdEncoding.EncodeToString(sum[:]))
+
+	req, err := mixin.signedRequest("POST", "http://example.com/api",
+		map[string]string{"oauth_consumer_key": "key"}, "secretkey", nil, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("signedRequest() error = %v", err)
+	}
+
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		t.Errorf("Content-Type = %q, want empty for a non-form body", ct)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if !bytes.Equal(body, payload) {
+		t.Errorf("body = %q, want %q", body, payload)
+	}
+
+	auth := req.Header.Get("Authorization")
+	if !strings.Contains(auth, `oauth_signature="SIGNATURE"`) {
+		t.Errorf("Authorization = %q, want it to contain oauth_signature=%q", auth, "SIGNATURE")
+	}
+	wantParam := `oauth_body_hash="` + wantHash + `"`
+	if !strings.Contains(auth, wantParam) {
+		t.Errorf("Authorization = %q, want it to contain %s", auth, wantParam)
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// RedisTokenStore stores oauth_token_secret values in Redis, so the
+// AuthorizeRedirect and AuthorizeToken steps can be handled by different
+// instances behind a load balancer.
+type RedisTokenStore struct {
+	Pool *redis.Pool
+
+	// Prefix is prepended to the oauth_token to form the Redis key.
+	// Defaults to "oauth1:" if empty.
+	Prefix string
+}
+
+func (s *RedisTokenStore) prefix() string {
+	if s.Prefix != "" {
+		return s.Prefix
+	}
+	return "oauth1:"
+}
+
+func (s *RedisTokenStore) Get(token string) (string, bool) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	secret, err := redis.String(conn.Do("GET", s.prefix()+token))
+	if err != nil {
+		return "", false
+	}
+	return secret, true
+}
+
+func (s *RedisTokenStore) Set(token, secret string, ttl time.Duration) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	conn.Do("SET", s.prefix()+token, secret, "EX", int(ttl.Seconds()))
+}
+
+func (s *RedisTokenStore) Delete(token string) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	conn.Do("DEL", s.prefix()+token)
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+)
+
+// TestHMACSignerSign checks that HMACSigner.Sign produces the base64-encoded
+// HMAC-SHA1 of base keyed by key, per RFC 5849 §3.4.2.
+func TestHMACSignerSign(t *testing.T) {
+	base, key := "base string", "consumersecret&tokensecret"
+
+	mac := hmac.New(sha1.New, []byte(key))
+	mac.Write([]byte(base))
+	want := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	got, err := HMACSigner{}.Sign(base, key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
+
+// TestPlaintextSignerSign checks that PlaintextSigner.Sign returns key
+// unchanged, per RFC 5849 §3.4.4.
+func TestPlaintextSignerSign(t *testing.T) {
+	got, err := PlaintextSigner{}.Sign("base string", "consumersecret&tokensecret")
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if got != "consumersecret&tokensecret" {
+		t.Errorf("Sign() = %q, want the key unencoded", got)
+	}
+}
+
+func generateTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+// verifyRSASignature fails the test if signature isn't a valid RSA-SHA1
+// signature of base under key.Public().
+func verifyRSASignature(t *testing.T, key *rsa.PrivateKey, base, signature string) {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		t.Fatalf("decoding signature: %v", err)
+	}
+	hashed := sha1.Sum([]byte(base))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, hashed[:], raw); err != nil {
+		t.Errorf("VerifyPKCS1v15() error = %v, signature does not verify", err)
+	}
+}
+
+// TestRSASignerSign checks that RSASigner.Sign produces a base64-encoded
+// RSA-SHA1 signature that verifies against the signer's public key.
+func TestRSASignerSign(t *testing.T) {
+	key := generateTestRSAKey(t)
+	signer := RSASigner{PrivateKey: key}
+
+	signature, err := signer.Sign("base string", "unused for RSA-SHA1")
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	verifyRSASignature(t, key, "base string", signature)
+}
+
+// TestNewRSASignerFromPEMPKCS1 checks that NewRSASignerFromPEM parses a
+// PKCS#1-encoded RSA private key and produces a working signer.
+func TestNewRSASignerFromPEMPKCS1(t *testing.T) {
+	key := generateTestRSAKey(t)
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	signer, err := NewRSASignerFromPEM(pemBytes)
+	if err != nil {
+		t.Fatalf("NewRSASignerFromPEM() error = %v", err)
+	}
+
+	signature, err := signer.Sign("base string", "unused for RSA-SHA1")
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	verifyRSASignature(t, key, "base string", signature)
+}
+
+// TestNewRSASignerFromPEMPKCS8 checks that NewRSASignerFromPEM falls back to
+// PKCS#8 parsing when the PEM block isn't a PKCS#1 key.
+func TestNewRSASignerFromPEMPKCS8(t *testing.T) {
+	key := generateTestRSAKey(t)
+	pkcs8Bytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey() error = %v", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: pkcs8Bytes,
+	})
+
+	signer, err := NewRSASignerFromPEM(pemBytes)
+	if err != nil {
+		t.Fatalf("NewRSASignerFromPEM() error = %v", err)
+	}
+
+	signature, err := signer.Sign("base string", "unused for RSA-SHA1")
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	verifyRSASignature(t, key, "base string", signature)
+}
+
+// TestNewRSASignerFromPEMInvalid checks that NewRSASignerFromPEM rejects
+// input that isn't a PEM block at all.
+func TestNewRSASignerFromPEMInvalid(t *testing.T) {
+	if _, err := NewRSASignerFromPEM([]byte("not a pem block")); err == nil {
+		t.Errorf("NewRSASignerFromPEM() error = nil, want an error for non-PEM input")
+	}
+}
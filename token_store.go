@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"time"
+
+	"code.google.com/p/vitess/go/cache"
+)
+
+// DefaultTokenTTL bounds how long a request token's secret is retained by a
+// TokenStore when OAuth1Mixin.TokenTTL is unset.
+const DefaultTokenTTL = 10 * time.Minute
+
+// TokenStore persists the oauth_token_secret between the redirect to the
+// provider's login screen (AuthorizeRedirect) and the callback that
+// exchanges it for an access token (AuthorizeToken). The default, used when
+// OAuth1Mixin.Tokens is nil, is an in-process LRU cache; CookieTokenStore and
+// RedisTokenStore are provided for deployments with multiple instances or
+// that need the secret to survive a restart.
+type TokenStore interface {
+	// Get returns the secret stored for token, and whether it was found.
+	Get(token string) (secret string, ok bool)
+
+	// Set stores secret for token, expiring it after ttl.
+	Set(token, secret string, ttl time.Duration)
+
+	// Delete removes any secret stored for token.
+	Delete(token string)
+}
+
+// tokenCacheItem is the value stored in the LRU cache, implementing the
+// cache.Value interface so it can be stored in a vitess LRUCache.
+type tokenCacheItem struct {
+	secret  string
+	expires time.Time
+}
+
+func (i tokenCacheItem) Size() int { return len(i.secret) }
+
+// lruTokenStore is the default TokenStore: a package-level in-process LRU
+// cache. It does not survive restarts and only works for single-instance
+// deployments.
+type lruTokenStore struct {
+	cache *cache.LRUCache
+}
+
+// defaultTokenStore backs OAuth1Mixin when no TokenStore is configured,
+// preserving the historical in-memory behavior. By default it stores 1MB of
+// data; when the limit is reached the cache clears out older items, which by
+// the time they are removed should not be needed anymore.
+var defaultTokenStore = &lruTokenStore{cache: cache.NewLRUCache(1048576)}
+
+func (s *lruTokenStore) Get(token string) (string, bool) {
+	value, ok := s.cache.Get(token)
+	if !ok {
+		return "", false
+	}
+	item := value.(tokenCacheItem)
+	if time.Now().After(item.expires) {
+		s.cache.Delete(token)
+		return "", false
+	}
+	return item.secret, true
+}
+
+func (s *lruTokenStore) Set(token, secret string, ttl time.Duration) {
+	s.cache.Set(token, tokenCacheItem{secret: secret, expires: time.Now().Add(ttl)})
+}
+
+func (s *lruTokenStore) Delete(token string) {
+	s.cache.Delete(token)
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"code.google.com/p/vitess/go/cache"
+)
+
+// TestLRUTokenStoreExpiry checks that lruTokenStore returns a stored secret
+// before its TTL elapses, and treats it as gone once it has.
+func TestLRUTokenStoreExpiry(t *testing.T) {
+	store := &lruTokenStore{cache: cache.NewLRUCache(1024)}
+
+	store.Set("token", "secret", time.Hour)
+	if secret, ok := store.Get("token"); !ok || secret != "secret" {
+		t.Fatalf("Get(token) = (%q, %v), want (\"secret\", true)", secret, ok)
+	}
+
+	store.Set("expired", "secret", -time.Second)
+	if _, ok := store.Get("expired"); ok {
+		t.Errorf("Get(expired) ok = true, want false for a token past its TTL")
+	}
+}
+
+// TestLRUTokenStoreDelete checks that Delete removes a token's secret.
+func TestLRUTokenStoreDelete(t *testing.T) {
+	store := &lruTokenStore{cache: cache.NewLRUCache(1024)}
+
+	store.Set("token", "secret", time.Hour)
+	store.Delete("token")
+
+	if _, ok := store.Get("token"); ok {
+		t.Errorf("Get(token) ok = true after Delete, want false")
+	}
+}